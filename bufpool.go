@@ -0,0 +1,50 @@
+package cblog
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// maxPooledBufferCap mirrors the threshold the fmt package itself uses to
+// stop pooling printers whose buffer has grown unusually large, so one
+// oversized log line doesn't keep a multi-megabyte buffer pinned in the
+// pool forever.
+const maxPooledBufferCap = 64 * 1024
+
+var messageBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 256)
+		return &buf
+	},
+}
+
+func getMessageBuffer() *[]byte {
+	return messageBufferPool.Get().(*[]byte)
+}
+
+func putMessageBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBufferCap {
+		return
+	}
+	*buf = (*buf)[:0]
+	messageBufferPool.Put(buf)
+}
+
+// formatMessage expands format/a into the final log message using a buffer
+// borrowed from messageBufferPool instead of the fresh allocation
+// fmt.Sprintf would make, since this runs on every emitted log line.
+func formatMessage(format string, a []interface{}) string {
+	if len(a) == 0 {
+		return format
+	}
+
+	bufPtr := getMessageBuffer()
+	defer putMessageBuffer(bufPtr)
+
+	buf := bytes.NewBuffer((*bufPtr)[:0])
+	_, _ = fmt.Fprintf(buf, format, a...)
+	*bufPtr = buf.Bytes()
+
+	return buf.String()
+}
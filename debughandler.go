@@ -0,0 +1,136 @@
+package cblog
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type facilityDto struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Level       string `json:"level"`
+	Following   bool   `json:"following"`
+}
+
+type logEntryDto struct {
+	Time     int64                  `json:"time"`
+	Category string                 `json:"category"`
+	Level    string                 `json:"level"`
+	File     string                 `json:"file"`
+	Line     int                    `json:"line"`
+	Message  string                 `json:"message"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+}
+
+// DebugHandler returns an http.Handler serving two endpoints for runtime
+// introspection of this logger's facilities:
+//
+//	GET  /facilities        list name/description/level for every registered facility
+//	POST /facilities        body is a JSON object of facility name to level name or "off",
+//	                         e.g. {"mypkg":"debug"} or {"mypkg":"off"}
+//	GET  /log?since=<nanos> ring buffer entries with Time.UnixNano() after since
+func (l *Logger) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/facilities", l.handleFacilities)
+	mux.HandleFunc("/log", l.handleLog)
+
+	return mux
+}
+
+func (l *Logger) handleFacilities(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		facilities := l.facilities.list()
+		dtos := make([]facilityDto, 0, len(facilities))
+		for _, f := range facilities {
+			dtos = append(dtos, facilityDto{
+				Name:        f.Name,
+				Description: f.Description,
+				Level:       f.Level().String(),
+				Following:   f.Following(),
+			})
+		}
+		writeJson(w, dtos)
+	case http.MethodPost:
+		l.handleSetFacilityLevels(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (l *Logger) handleSetFacilityLevels(w http.ResponseWriter, r *http.Request) {
+	var levels map[string]string
+	if e := json.NewDecoder(r.Body).Decode(&levels); e != nil {
+		http.Error(w, e.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for name, value := range levels {
+		f := l.facilities.get(name)
+		if f == nil {
+			http.Error(w, "unknown facility: "+name, http.StatusNotFound)
+			return
+		}
+
+		if strings.EqualFold(value, "off") {
+			// Put the facility in follow mode rather than snapshotting the
+			// current default: ShouldLog always defers to a registered
+			// facility's own level once one exists, so pinning this to
+			// today's default would decouple the facility from any later
+			// SetLevel call on the logger.
+			f.SetOff()
+			continue
+		}
+
+		level, ok := parseLogLevel(value)
+		if !ok {
+			http.Error(w, "unknown level: "+value, http.StatusBadRequest)
+			return
+		}
+		f.SetLevel(level)
+	}
+
+	l.syncUnderlyingLevel()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (l *Logger) handleLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	since := time.Time{}
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		nanos, e := strconv.ParseInt(raw, 10, 64)
+		if e != nil {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+		since = time.Unix(0, nanos)
+	}
+
+	entries := l.ring.since(since)
+	dtos := make([]logEntryDto, 0, len(entries))
+	for _, entry := range entries {
+		dtos = append(dtos, logEntryDto{
+			Time:     entry.Time.UnixNano(),
+			Category: entry.Category,
+			Level:    entry.Level.String(),
+			File:     entry.File,
+			Line:     entry.Line,
+			Message:  entry.Message,
+			Fields:   entry.Fields,
+		})
+	}
+	writeJson(w, dtos)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,136 @@
+package cblog
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Facility is a named, independently levelled debug category, modelled on
+// syncthing's facility/BeforeCompactionHook approach of letting individual
+// packages have their own verbosity without raising it for everyone.
+type Facility struct {
+	Name        string
+	Description string
+
+	level     atomic.Int32
+	following atomic.Bool
+
+	// defaultLevel is the owning Logger's own level field. Level consults
+	// it instead of the facility's own level while following is true, so a
+	// facility turned off with SetOff tracks later SetLevel calls on the
+	// logger instead of freezing at whatever the default happened to be at
+	// the moment it was turned off.
+	defaultLevel *atomic.Int32
+}
+
+// Level returns the facility's current LogLevel: its own level, or the
+// owning Logger's live default LogLevel if SetOff last put it in follow
+// mode.
+func (f *Facility) Level() LogLevel {
+	if f.following.Load() {
+		return LogLevel(f.defaultLevel.Load())
+	}
+
+	return LogLevel(f.level.Load())
+}
+
+// Following reports whether the facility is currently tracking the owning
+// Logger's default level rather than its own, i.e. whether it was last
+// turned off with SetOff.
+func (f *Facility) Following() bool {
+	return f.following.Load()
+}
+
+// SetLevel changes the facility's current LogLevel, taking it out of follow
+// mode if SetOff had previously put it there.
+func (f *Facility) SetLevel(level LogLevel) {
+	f.following.Store(false)
+	f.level.Store(int32(level))
+}
+
+// SetOff puts the facility into follow mode: Level reports the owning
+// Logger's live default LogLevel from now on, continuously, until SetLevel
+// is called again - rather than freezing at whatever that default happens
+// to be right now.
+func (f *Facility) SetOff() {
+	f.following.Store(true)
+}
+
+type facilityRegistry struct {
+	mu         sync.RWMutex
+	facilities map[string]*Facility
+
+	// defaultLevel is shared with every Facility this registry creates, so
+	// SetOff can make a facility follow it. See Logger.level.
+	defaultLevel *atomic.Int32
+}
+
+func newFacilityRegistry(defaultLevel *atomic.Int32) *facilityRegistry {
+	return &facilityRegistry{
+		facilities:   map[string]*Facility{},
+		defaultLevel: defaultLevel,
+	}
+}
+
+func (r *facilityRegistry) register(name, description string, level LogLevel) *Facility {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.facilities[name]; ok {
+		f.Description = description
+		f.SetLevel(level)
+		return f
+	}
+
+	f := &Facility{
+		Name:         name,
+		Description:  description,
+		defaultLevel: r.defaultLevel,
+	}
+	f.SetLevel(level)
+	r.facilities[name] = f
+
+	return f
+}
+
+func (r *facilityRegistry) get(name string) *Facility {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.facilities[name]
+}
+
+func (r *facilityRegistry) list() []*Facility {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]*Facility, 0, len(r.facilities))
+	for _, f := range r.facilities {
+		out = append(out, f)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
+
+// maxLevel returns the most permissive (numerically highest) LogLevel among
+// every registered facility, or 0 if none are registered. Logger uses this
+// to keep the embedded go-logger's own level gate from silently dropping a
+// message that ShouldLog said should pass.
+func (r *facilityRegistry) maxLevel() LogLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var max LogLevel
+	for _, f := range r.facilities {
+		if level := f.Level(); level > max {
+			max = level
+		}
+	}
+
+	return max
+}
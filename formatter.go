@@ -0,0 +1,191 @@
+package cblog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a structured snapshot of a single log call: the same data the
+// text template would expand into a line, available to a Formatter (or the
+// debug ring/HTTP handler) before it has been turned into bytes.
+type Entry struct {
+	Time     time.Time
+	Level    LogLevel
+	Category string
+	File     string
+	Line     int
+	Message  string
+	Fields   map[string]interface{}
+}
+
+// Formatter turns an Entry into the bytes written to a Logger's sinks. When
+// LoggerConfig.Formatter is nil, a Logger falls back to the upstream
+// go-logger package's %{placeholder} template instead of calling a
+// Formatter at all.
+type Formatter interface {
+	Format(entry Entry) []byte
+}
+
+// TextFormatter reimplements the %{placeholder} template so that fields
+// attached via Fields calls or Logger.With can still be logged as text,
+// something the upstream template has no placeholder for. Supported
+// placeholders: %{time[:layout]}, %{category}, %{level}, %{file}, %{line},
+// %{message}; an empty Layout falls back to DefaultLoggerConfig's Format.
+type TextFormatter struct {
+	Layout string
+}
+
+func (f TextFormatter) Format(entry Entry) []byte {
+	format := f.layoutOrDefault()
+
+	bufPtr := getMessageBuffer()
+	defer putMessageBuffer(bufPtr)
+	buf := bytes.NewBuffer((*bufPtr)[:0])
+
+	for i := 0; i < len(format); {
+		if format[i] != '%' || i+1 >= len(format) || format[i+1] != '{' {
+			buf.WriteByte(format[i])
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(format[i+2:], '}')
+		if end == -1 {
+			buf.WriteByte(format[i])
+			i++
+			continue
+		}
+
+		token := format[i+2 : i+2+end]
+		name, layout := token, ""
+		if idx := strings.IndexByte(token, ':'); idx != -1 {
+			name, layout = token[:idx], token[idx+1:]
+		}
+		writeTextPlaceholder(buf, entry, name, layout)
+
+		i += 2 + end + 1
+	}
+
+	if len(entry.Fields) > 0 {
+		buf.WriteByte(' ')
+		buf.WriteString(formatFieldsText(entry.Fields))
+	}
+
+	*bufPtr = buf.Bytes()
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+
+	return out
+}
+
+func (f TextFormatter) layoutOrDefault() string {
+	if f.Layout != "" {
+		return f.Layout
+	}
+
+	return DefaultLoggerConfig().Format
+}
+
+func writeTextPlaceholder(buf *bytes.Buffer, entry Entry, name, layout string) {
+	switch name {
+	case "time":
+		if layout == "" {
+			layout = "2006-01-02 15:04:05.000 -0700"
+		}
+		buf.WriteString(entry.Time.Format(layout))
+	case "category":
+		buf.WriteString(entry.Category)
+	case "level":
+		buf.WriteString(entry.Level.String())
+	case "file":
+		buf.WriteString(entry.File)
+	case "line":
+		buf.WriteString(strconv.Itoa(entry.Line))
+	case "message":
+		buf.WriteString(entry.Message)
+	}
+}
+
+// JSONFormatter emits one JSON object per Entry, suitable for ingestion by
+// Loki/ELK-style collectors without a regex parser.
+type JSONFormatter struct{}
+
+type jsonEntry struct {
+	Time     string                 `json:"time"`
+	Level    string                 `json:"level"`
+	Category string                 `json:"category"`
+	File     string                 `json:"file"`
+	Line     int                    `json:"line"`
+	Message  string                 `json:"msg"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (JSONFormatter) Format(entry Entry) []byte {
+	bufPtr := getMessageBuffer()
+	defer putMessageBuffer(bufPtr)
+	buf := bytes.NewBuffer((*bufPtr)[:0])
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(jsonEntry{
+		Time:     entry.Time.Format(time.RFC3339Nano),
+		Level:    entry.Level.String(),
+		Category: entry.Category,
+		File:     entry.File,
+		Line:     entry.Line,
+		Message:  entry.Message,
+		Fields:   entry.Fields,
+	})
+
+	// json.Encoder.Encode always appends a trailing newline; Logger's
+	// writer adds its own, so trim it here to avoid writing blank lines.
+	line := bytes.TrimRight(buf.Bytes(), "\n")
+
+	*bufPtr = append(buf.Bytes()[:0], line...)
+	out := make([]byte, len(line))
+	copy(out, line)
+
+	return out
+}
+
+// formatFieldsText renders fields as sorted "key=value" pairs for
+// TextFormatter and for the plain-text fallback Fields calls use when no
+// Formatter is configured.
+func formatFieldsText(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// mergeFields combines a Logger's base fields (see Logger.With) with fields
+// passed at the call site, the latter taking precedence. Returns nil if
+// both are empty so callers can treat "no fields" as a nil map.
+func mergeFields(base, extra map[string]interface{}) map[string]interface{} {
+	if len(base) == 0 && len(extra) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]interface{}, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+
+	return merged
+}
@@ -0,0 +1,108 @@
+package cblog
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestJSONFormatterEscaping(t *testing.T) {
+	entry := Entry{
+		Category: "cat",
+		Message:  "line one\nline two\t\"quoted\" <tag> & 日本語",
+		Fields: map[string]interface{}{
+			"note": "has\nnewline and \"quotes\"",
+		},
+	}
+
+	out := JSONFormatter{}.Format(entry)
+
+	var decoded struct {
+		Message string            `json:"msg"`
+		Fields  map[string]string `json:"fields"`
+	}
+	if e := json.Unmarshal(out, &decoded); e != nil {
+		t.Fatalf("JSONFormatter produced invalid JSON: %v\noutput: %s", e, out)
+	}
+
+	if decoded.Message != entry.Message {
+		t.Fatalf("message round-trip mismatch: got %q, want %q", decoded.Message, entry.Message)
+	}
+	if decoded.Fields["note"] != entry.Fields["note"] {
+		t.Fatalf("field round-trip mismatch: got %q, want %q", decoded.Fields["note"], entry.Fields["note"])
+	}
+
+	// SetEscapeHTML(false) means '<', '>' and '&' must come through
+	// unescaped rather than as backslash-u escape sequences.
+	if !contains(out, []byte("<tag>")) {
+		t.Fatalf("expected unescaped HTML characters in output, got: %s", out)
+	}
+}
+
+func TestJSONFormatterNoTrailingBlankLine(t *testing.T) {
+	out := JSONFormatter{}.Format(Entry{Message: "hello"})
+
+	if len(out) > 0 && out[len(out)-1] == '\n' {
+		t.Fatalf("expected trailing newline to be trimmed, got: %q", out)
+	}
+}
+
+func TestMergeFields(t *testing.T) {
+	base := map[string]interface{}{"a": 1, "b": 2}
+	extra := map[string]interface{}{"b": 3, "c": 4}
+
+	merged := mergeFields(base, extra)
+
+	if merged["a"] != 1 || merged["b"] != 3 || merged["c"] != 4 {
+		t.Fatalf("unexpected merge result: %#v", merged)
+	}
+
+	// base and extra must be left untouched.
+	if base["b"] != 2 {
+		t.Fatalf("mergeFields mutated its base argument: %#v", base)
+	}
+
+	if mergeFields(nil, nil) != nil {
+		t.Fatalf("expected mergeFields(nil, nil) to return nil")
+	}
+}
+
+func TestLoggerWithMergesFields(t *testing.T) {
+	l, e := NewLogger(LoggerConfig{
+		LogLevel:    DebugLevel,
+		LogToStdOut: false,
+		AdditionalWriters: []io.Writer{
+			io.Discard,
+		},
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer func() { _ = l.Close() }()
+
+	parent := l.With(map[string]interface{}{"a": 1, "b": 2})
+	child := parent.With(map[string]interface{}{"b": 3, "c": 4})
+
+	if len(parent.fields) != 2 || parent.fields["a"] != 1 || parent.fields["b"] != 2 {
+		t.Fatalf("unexpected parent fields: %#v", parent.fields)
+	}
+
+	if len(child.fields) != 3 || child.fields["a"] != 1 || child.fields["b"] != 3 || child.fields["c"] != 4 {
+		t.Fatalf("unexpected child fields: %#v", child.fields)
+	}
+
+	// Child's fields must not alias or mutate the parent's.
+	child.fields["a"] = 99
+	if parent.fields["a"] != 1 {
+		t.Fatalf("child.With mutated parent fields: %#v", parent.fields)
+	}
+}
+
+func contains(haystack, needle []byte) bool {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if string(haystack[i:i+len(needle)]) == string(needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -8,15 +8,28 @@ import (
 	"log"
 	"net"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// MultipleWriter fans a single formatted line out to every configured sink.
+// The lock is taken once for the whole fan-out rather than relying on each
+// sink serializing its own writes. It is held until every sink has been
+// written, so a slow sink delays the remaining sinks and any concurrent
+// Write call.
 type MultipleWriter struct {
 	writers []io.Writer
+	mu      sync.Mutex
 }
 
-func (m MultipleWriter) Write(p []byte) (n int, err error) {
+func (m *MultipleWriter) Write(p []byte) (n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	for _, writer := range m.writers {
 		n, e := writer.Write(p)
 		if e != nil {
@@ -27,8 +40,8 @@ func (m MultipleWriter) Write(p []byte) (n int, err error) {
 	return n, nil
 }
 
-func NewMultipleWriter(writers ...io.Writer) MultipleWriter {
-	return MultipleWriter{writers:writers}
+func NewMultipleWriter(writers ...io.Writer) *MultipleWriter {
+	return &MultipleWriter{writers: writers}
 }
 
 type LogLevel int
@@ -42,6 +55,44 @@ const (
 	DebugLevel
 )
 
+func (l LogLevel) String() string {
+	switch l {
+	case CriticalLevel:
+		return "critical"
+	case ErrorLevel:
+		return "error"
+	case WarningLevel:
+		return "warning"
+	case NoticeLevel:
+		return "notice"
+	case InfoLevel:
+		return "info"
+	case DebugLevel:
+		return "debug"
+	default:
+		return "unknown"
+	}
+}
+
+func parseLogLevel(s string) (LogLevel, bool) {
+	switch strings.ToLower(s) {
+	case "critical":
+		return CriticalLevel, true
+	case "error":
+		return ErrorLevel, true
+	case "warning":
+		return WarningLevel, true
+	case "notice":
+		return NoticeLevel, true
+	case "info":
+		return InfoLevel, true
+	case "debug":
+		return DebugLevel, true
+	default:
+		return 0, false
+	}
+}
+
 const (
 	Black = iota + 30
 	Red
@@ -66,23 +117,41 @@ type LoggerConfig struct {
 	AdditionalWriters       []io.Writer
 	AdditionalWriterClosers []io.WriteCloser
 	SetAsDefaultLogger      bool
-	/*
-		todo:
-			ErrorReporter           func (e error)
-			Rotate                  bool
-			RotateFileSize          uint64
-			RotateKeepCount         int
-			Upload                  bool
-			UploadInterval          time.Duration
-			Uploader                func(fileName string, content []byte) error
-	*/
+	ErrorReporter           func(e error)
+	Rotate                  bool
+	RotateFileSize          uint64
+	RotateLines             uint64
+	RotateDaily             bool
+	RotateKeepCount         int
+	MaxDays                 int
+	BeforeRotate            func(currentPath string, info os.FileInfo) error
+	AfterRotate             func(archivedPath, currentPath string, info os.FileInfo) error
+	Upload                  bool
+	UploadInterval          time.Duration
+	Uploader                func(fileName string, content []byte) error
+	RingBufferSize          int
+	// Formatter, when set, is consulted instead of the %{placeholder} text
+	// template above to turn each log call into bytes. See TextFormatter
+	// and JSONFormatter.
+	Formatter Formatter
 }
 
 type Logger struct {
-	defaultFile *os.File
-	config LoggerConfig
-	logger *logger.Logger
-	closers []io.Closer
+	defaultFile *rotatingFile
+	config      LoggerConfig
+	logger      *logger.Logger
+	writer      io.Writer
+	closers     []io.Closer
+	facilities  *facilityRegistry
+	ring        *ringBuffer
+	level       atomic.Int32
+	fields      map[string]interface{}
+	// posMu guards GetPosOverride/SetPosOverride on the embedded
+	// *logger.Logger, which has no locking of its own even though this
+	// package mutates it around every call made through it. It's a
+	// pointer so Loggers created via With share the same lock as the
+	// parent they wrap the same *logger.Logger for.
+	posMu *sync.Mutex
 }
 
 func DefaultLoggerConfig() LoggerConfig {
@@ -90,7 +159,7 @@ func DefaultLoggerConfig() LoggerConfig {
 		LogLevel:    InfoLevel,
 		Format:      "%{time:2006-01-02 15:04:05.000 -0700} : %{category} : %{level} : %{file}:%{line} : %{message}",
 		LogToStdOut: true,
-		FilePerm: os.ModePerm,
+		FilePerm:    os.ModePerm,
 	}
 }
 
@@ -100,10 +169,14 @@ func NewLogger(config LoggerConfig) (*Logger, error) {
 
 	cblogger := &Logger{
 		config: config,
+		ring:   newRingBuffer(config.RingBufferSize),
+		posMu:  &sync.Mutex{},
 	}
+	cblogger.level.Store(int32(config.LogLevel))
+	cblogger.facilities = newFacilityRegistry(&cblogger.level)
 
 	if config.LogToFile {
-		wr, e := os.OpenFile(config.FilePath, os.O_RDWR|os.O_APPEND|os.O_CREATE, config.FilePerm)
+		wr, e := newRotatingFile(config)
 		if e != nil {
 			return nil, e
 		}
@@ -131,7 +204,10 @@ func NewLogger(config LoggerConfig) (*Logger, error) {
 		}
 	}
 
-	l, e := logger.New(NewMultipleWriter(writers...), config.StdOutColor, config.LogLevel)
+	mw := NewMultipleWriter(writers...)
+	cblogger.writer = mw
+
+	l, e := logger.New(mw, config.StdOutColor, logger.LogLevel(config.LogLevel))
 	if e != nil {
 		return nil, e
 	}
@@ -153,6 +229,81 @@ func (l *Logger) GetUnderlyingLogger() *logger.Logger {
 	return l.logger
 }
 
+// RegisterFacility declares a category (typically a package name) as a
+// debug facility with its own runtime-togglable LogLevel, independent of
+// config.LogLevel. It can be called again later to change the description
+// or level of an already-registered facility.
+func (l *Logger) RegisterFacility(category, description string, level LogLevel) *Facility {
+	f := l.facilities.register(category, description, level)
+	l.syncUnderlyingLevel()
+
+	return f
+}
+
+// ShouldLog reports whether a message at level would actually be emitted
+// for category, consulting the category's facility level if one is
+// registered and falling back to the logger's current default level (see
+// Level) otherwise. Callers should
+// use this to skip expensive formatting (hex dumps, JSON marshalling) when
+// debug is off for their facility.
+func (l *Logger) ShouldLog(category string, level LogLevel) bool {
+	threshold := l.Level()
+	if f := l.facilities.get(category); f != nil {
+		threshold = f.Level()
+	}
+
+	return level <= threshold
+}
+
+// Level returns the logger's current default LogLevel, read without a lock
+// so it can be consulted on every log call.
+func (l *Logger) Level() LogLevel {
+	return LogLevel(l.level.Load())
+}
+
+// SetLevel changes the logger's default LogLevel at runtime. Categories
+// with their own registered Facility are unaffected, except facilities that
+// SetOff has put in follow mode, which report this level from then on.
+func (l *Logger) SetLevel(level LogLevel) {
+	l.level.Store(int32(level))
+	l.syncUnderlyingLevel()
+}
+
+// syncUnderlyingLevel raises the embedded go-logger's own level gate - set
+// once from config.LogLevel in NewLogger and otherwise frozen - to the most
+// permissive level anyone is currently allowed to log at, across the
+// default LogLevel and every registered facility. Without this, ShouldLog
+// can say a message should be emitted while the embedded logger's worker
+// silently drops it because its own gate never moved past NewLogger's
+// config.
+func (l *Logger) syncUnderlyingLevel() {
+	level := l.Level()
+	if facilityLevel := l.facilities.maxLevel(); facilityLevel > level {
+		level = facilityLevel
+	}
+
+	l.logger.SetLogLevel(logger.LogLevel(level))
+}
+
+// withPosOverride serializes access to the embedded go-logger's
+// posOverride field - unsynchronized in that package, but mutated by every
+// call made through l.logger - then runs fn with it set to pos, restoring
+// the previous value before returning. fn runs the underlying write, so
+// this still fully serializes every call on the non-Formatter path; it is
+// a correctness fix for the shared posOverride field, not a concurrency
+// improvement. Configuring Formatter avoids l.logger (and this lock)
+// entirely via writeEntry - see BenchmarkConcurrentInfoF_Formatter.
+func (l *Logger) withPosOverride(pos int, fn func()) {
+	l.posMu.Lock()
+	defer l.posMu.Unlock()
+
+	prev := l.logger.GetPosOverride()
+	l.logger.SetPosOverride(pos)
+	defer l.logger.SetPosOverride(prev)
+
+	fn()
+}
+
 func (l *Logger) Close() error {
 	var es []string
 	for _, closer := range l.closers {
@@ -170,97 +321,249 @@ func (l *Logger) Close() error {
 }
 
 func (l *Logger) FatalF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.FatalF(category, format, a...)
-	} else {
-		l.logger.Fatal(category, format)
-	}
+	l.withPosOverride(3, func() {
+		if len(a) > 0 {
+			l.logger.FatalF(category, format, a...)
+		} else {
+			l.logger.Fatal(category, format)
+		}
+	})
 }
 
 func (l *Logger) PanicF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.PanicF(category, format, a...)
-	} else {
-		l.logger.Panic(category, format)
-	}
+	l.withPosOverride(3, func() {
+		if len(a) > 0 {
+			l.logger.PanicF(category, format, a...)
+		} else {
+			l.logger.Panic(category, format)
+		}
+	})
 }
 
 func (l *Logger) CriticalF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.CriticalF(category, format, a...)
-	} else {
-		l.logger.Critical(category, format)
-	}
+	l.leveledF(CriticalLevel, category, format, a)
 }
 
 func (l *Logger) ErrorF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.ErrorF(category, format, a...)
-	} else {
-		l.logger.Error(category, format)
-	}
+	l.leveledF(ErrorLevel, category, format, a)
 }
 
 func (l *Logger) WarningF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.WarningF(category, format, a...)
-	} else {
-		l.logger.Warning(category, format)
-	}
+	l.leveledF(WarningLevel, category, format, a)
 }
 
 func (l *Logger) NoticeF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.NoticeF(category, format, a...)
-	} else {
-		l.logger.Notice(category, format)
-	}
+	l.leveledF(NoticeLevel, category, format, a)
 }
 
 func (l *Logger) InfoF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.InfoF(category, format, a...)
-	} else {
-		l.logger.Info(category, format)
-	}
+	l.leveledF(InfoLevel, category, format, a)
 }
 
 func (l *Logger) DebugF(category, format string, a ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
-	if len(a) > 0 {
-		l.logger.DebugF(category, format, a...)
-	} else {
-		l.logger.Debug(category, format)
+	l.leveledF(DebugLevel, category, format, a)
+}
+
+func (l *Logger) CriticalFields(category, msg string, fields map[string]interface{}) {
+	l.leveledFields(CriticalLevel, category, msg, fields)
+}
+
+func (l *Logger) ErrorFields(category, msg string, fields map[string]interface{}) {
+	l.leveledFields(ErrorLevel, category, msg, fields)
+}
+
+func (l *Logger) WarningFields(category, msg string, fields map[string]interface{}) {
+	l.leveledFields(WarningLevel, category, msg, fields)
+}
+
+func (l *Logger) NoticeFields(category, msg string, fields map[string]interface{}) {
+	l.leveledFields(NoticeLevel, category, msg, fields)
+}
+
+func (l *Logger) InfoFields(category, msg string, fields map[string]interface{}) {
+	l.leveledFields(InfoLevel, category, msg, fields)
+}
+
+func (l *Logger) DebugFields(category, msg string, fields map[string]interface{}) {
+	l.leveledFields(DebugLevel, category, msg, fields)
+}
+
+// With returns a child Logger that merges fields into the fields passed to
+// every subsequent Fields call (e.g. InfoFields), with call-site fields
+// taking precedence on key collisions. The child shares this Logger's
+// writers, ring buffer and facilities, but has its own copy of the current
+// LogLevel, so SetLevel on one does not affect the other after With returns.
+func (l *Logger) With(fields map[string]interface{}) *Logger {
+	child := &Logger{
+		defaultFile: l.defaultFile,
+		config:      l.config,
+		logger:      l.logger,
+		writer:      l.writer,
+		closers:     l.closers,
+		facilities:  l.facilities,
+		ring:        l.ring,
+		fields:      mergeFields(l.fields, fields),
+		posMu:       l.posMu,
 	}
+	child.level.Store(l.level.Load())
+
+	return child
+}
+
+// leveledF is the shared path for the per-level XxxF methods above. It
+// consults the per-category facility level (see ShouldLog) so that
+// expensive formatting can be skipped entirely when the category is quiet,
+// and tees anything that is actually emitted into the debug ring buffer.
+func (l *Logger) leveledF(level LogLevel, category, format string, a []interface{}) {
+	if !l.ShouldLog(category, level) {
+		return
+	}
+
+	message := formatMessage(format, a)
+
+	if l.config.Formatter != nil {
+		l.writeEntry(level, category, message, l.fields, 4)
+		return
+	}
+
+	l.withPosOverride(4, func() {
+		switch level {
+		case CriticalLevel:
+			if len(a) > 0 {
+				l.logger.CriticalF(category, format, a...)
+			} else {
+				l.logger.Critical(category, format)
+			}
+		case ErrorLevel:
+			if len(a) > 0 {
+				l.logger.ErrorF(category, format, a...)
+			} else {
+				l.logger.Error(category, format)
+			}
+		case WarningLevel:
+			if len(a) > 0 {
+				l.logger.WarningF(category, format, a...)
+			} else {
+				l.logger.Warning(category, format)
+			}
+		case NoticeLevel:
+			if len(a) > 0 {
+				l.logger.NoticeF(category, format, a...)
+			} else {
+				l.logger.Notice(category, format)
+			}
+		case InfoLevel:
+			if len(a) > 0 {
+				l.logger.InfoF(category, format, a...)
+			} else {
+				l.logger.Info(category, format)
+			}
+		case DebugLevel:
+			if len(a) > 0 {
+				l.logger.DebugF(category, format, a...)
+			} else {
+				l.logger.Debug(category, format)
+			}
+		}
+	})
+
+	l.writeRing(level, category, message, l.fields, 4)
+}
+
+// leveledFields is the Fields-call counterpart of leveledF: msg is already
+// the final message, with fields merged in and attached to the Entry
+// (and, when no Formatter is configured, appended as "key=value" pairs so
+// they aren't silently dropped).
+func (l *Logger) leveledFields(level LogLevel, category, msg string, fields map[string]interface{}) {
+	if !l.ShouldLog(category, level) {
+		return
+	}
+
+	merged := mergeFields(l.fields, fields)
+
+	if l.config.Formatter != nil {
+		l.writeEntry(level, category, msg, merged, 4)
+		return
+	}
+
+	message := msg
+	if len(merged) > 0 {
+		message = msg + " " + formatFieldsText(merged)
+	}
+
+	l.withPosOverride(4, func() {
+		switch level {
+		case CriticalLevel:
+			l.logger.Critical(category, message)
+		case ErrorLevel:
+			l.logger.Error(category, message)
+		case WarningLevel:
+			l.logger.Warning(category, message)
+		case NoticeLevel:
+			l.logger.Notice(category, message)
+		case InfoLevel:
+			l.logger.Info(category, message)
+		case DebugLevel:
+			l.logger.Debug(category, message)
+		}
+	})
+
+	l.writeRing(level, category, message, merged, 4)
+}
+
+// writeEntry is the Formatter-backed write path: it builds the Entry once,
+// hands it to config.Formatter directly (bypassing the upstream logger's
+// own template and writer), and tees it into the ring buffer exactly like
+// writeRing does for the non-Formatter path.
+func (l *Logger) writeEntry(level LogLevel, category, message string, fields map[string]interface{}, skip int) {
+	file, line := callerInfo(skip)
+	entry := Entry{
+		Time:     time.Now(),
+		Level:    level,
+		Category: category,
+		File:     file,
+		Line:     line,
+		Message:  message,
+		Fields:   fields,
+	}
+
+	data := append(l.config.Formatter.Format(entry), '\n')
+	if _, e := l.writer.Write(data); e != nil && l.config.ErrorReporter != nil {
+		l.config.ErrorReporter(e)
+	}
+
+	if l.ring != nil {
+		l.ring.add(entry)
+	}
+}
+
+func (l *Logger) writeRing(level LogLevel, category, message string, fields map[string]interface{}, skip int) {
+	if l.ring == nil {
+		return
+	}
+
+	file, line := callerInfo(skip)
+	l.ring.add(Entry{
+		Time:     time.Now(),
+		Level:    level,
+		Category: category,
+		File:     file,
+		Line:     line,
+		Message:  message,
+		Fields:   fields,
+	})
+}
+
+func callerInfo(skip int) (string, int) {
+	_, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		return "???", 0
+	}
+
+	return filepath.Base(file), line
 }
 
 func (l *Logger) StackAsError(category, message string) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
 	if message == "" {
 		message = "Stack info"
 	}
@@ -270,13 +573,12 @@ func (l *Logger) StackAsError(category, message string) {
 	newStackParts := []string{stackParts[0]}
 	newStackParts = append(newStackParts, stackParts[3:]...)
 	stack = strings.Join(newStackParts, "\n")
+	// ErrorF (via leveledF) sets its own pos override before this is ever
+	// read by the embedded logger, so there is nothing to override here.
 	l.ErrorF(category, message+stack)
 }
 
 func (l *Logger) StackAsCritical(category, message string) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(3)
-	defer l.logger.SetPosOverride(pos)
 	if message == "" {
 		message = "Stack info"
 	}
@@ -286,6 +588,9 @@ func (l *Logger) StackAsCritical(category, message string) {
 	newStackParts := []string{stackParts[0]}
 	newStackParts = append(newStackParts, stackParts[3:]...)
 	stack = strings.Join(newStackParts, "\n")
+	// CriticalF (via leveledF) sets its own pos override before this is
+	// ever read by the embedded logger, so there is nothing to override
+	// here.
 	l.CriticalF(category, message+stack)
 }
 
@@ -301,18 +606,18 @@ func Stack() string {
 	return stack
 }
 
-func (l *Logger) Write(bytes []byte) (int, error) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(5)
-	defer l.logger.SetPosOverride(pos)
-	return l.logger.Write(bytes)
+func (l *Logger) Write(bytes []byte) (n int, err error) {
+	l.withPosOverride(5, func() {
+		n, err = l.logger.Write(bytes)
+	})
+
+	return n, err
 }
 
 func (l *Logger) Print(v ...interface{}) {
-	pos := l.logger.GetPosOverride()
-	l.logger.SetPosOverride(5)
-	defer l.logger.SetPosOverride(pos)
-	l.logger.Print(v...)
+	l.withPosOverride(5, func() {
+		l.logger.Print(v...)
+	})
 }
 
 type UnixSocketLogger struct {
@@ -0,0 +1,60 @@
+package cblog
+
+import (
+	"io"
+	"testing"
+)
+
+// BenchmarkConcurrentInfoF drives InfoF from many goroutines at once on the
+// default (non-Formatter) path, which still serializes every call through
+// withPosOverride - see its doc comment. This benchmark exists to keep that
+// path honest under -race, not to show a concurrency win, e.g.:
+//
+//	go test -run NONE -bench ConcurrentInfoF -race ./...
+func BenchmarkConcurrentInfoF(b *testing.B) {
+	l, e := NewLogger(LoggerConfig{
+		LogLevel:    DebugLevel,
+		LogToStdOut: false,
+		AdditionalWriters: []io.Writer{
+			io.Discard,
+		},
+	})
+	if e != nil {
+		b.Fatal(e)
+	}
+	defer func() { _ = l.Close() }()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.InfoF("BENCH", "request id=%d status=%d took=%dms", 1, 200, 12)
+		}
+	})
+}
+
+// BenchmarkConcurrentInfoF_Formatter is the BenchmarkConcurrentInfoF
+// counterpart with a Formatter configured, which routes through writeEntry
+// instead of l.logger and therefore never touches withPosOverride's lock -
+// this is the path the atomics/pool redesign actually speeds up under
+// concurrency.
+func BenchmarkConcurrentInfoF_Formatter(b *testing.B) {
+	l, e := NewLogger(LoggerConfig{
+		LogLevel:    DebugLevel,
+		LogToStdOut: false,
+		Formatter:   &TextFormatter{},
+		AdditionalWriters: []io.Writer{
+			io.Discard,
+		},
+	})
+	if e != nil {
+		b.Fatal(e)
+	}
+	defer func() { _ = l.Close() }()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.InfoF("BENCH", "request id=%d status=%d took=%dms", 1, 200, 12)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package cblog
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultRingBufferSize = 250
+
+// ringBuffer is a fixed-size circular buffer of the most recently emitted
+// log lines, used to back the /log endpoint in DebugHandler. It stores the
+// same Entry a Formatter would receive, so DebugHandler can serve it as
+// JSON without re-parsing the formatted text line.
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	filled  bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	if size <= 0 {
+		size = defaultRingBufferSize
+	}
+
+	return &ringBuffer{
+		entries: make([]Entry, size),
+	}
+}
+
+func (r *ringBuffer) add(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = entry
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.filled = true
+	}
+}
+
+// since returns every buffered entry strictly after t, oldest first.
+func (r *ringBuffer) since(t time.Time) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ordered := make([]Entry, 0, len(r.entries))
+	if r.filled {
+		ordered = append(ordered, r.entries[r.next:]...)
+	}
+	ordered = append(ordered, r.entries[:r.next]...)
+
+	out := make([]Entry, 0, len(ordered))
+	for _, entry := range ordered {
+		if entry.Time.After(t) {
+			out = append(out, entry)
+		}
+	}
+
+	return out
+}
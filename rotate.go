@@ -0,0 +1,334 @@
+package cblog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// archiveSuffixPattern matches the "<YYYYMMDD-HHMMSS>" (optionally
+// ".<n>"-disambiguated) suffix archivePath appends after the base file
+// name, so pruneArchives only ever considers files this rotator actually
+// created.
+var archiveSuffixPattern = regexp.MustCompile(`^\d{8}-\d{6}(\.\d+)?$`)
+
+// rotatingFile is the io.Writer cblog hands to the underlying logger when
+// LoggerConfig.LogToFile is set. When config.Rotate is false it behaves like
+// a plain append-only *os.File; when true it watches bytes/lines/day
+// thresholds and swaps the file out from under the writer without the
+// caller noticing.
+type rotatingFile struct {
+	mu     sync.RWMutex
+	path   string
+	perm   os.FileMode
+	file   *os.File
+	day    string
+	config LoggerConfig
+
+	bytesWritten atomic.Uint64
+	linesWritten atomic.Uint64
+
+	uploadQueue chan string
+}
+
+func newRotatingFile(config LoggerConfig) (*rotatingFile, error) {
+	f, e := os.OpenFile(config.FilePath, os.O_RDWR|os.O_APPEND|os.O_CREATE, config.FilePerm)
+	if e != nil {
+		return nil, e
+	}
+
+	info, e := f.Stat()
+	if e != nil {
+		_ = f.Close()
+		return nil, e
+	}
+
+	r := &rotatingFile{
+		path:   config.FilePath,
+		perm:   config.FilePerm,
+		file:   f,
+		day:    info.ModTime().Format("20060102"),
+		config: config,
+	}
+	r.bytesWritten.Store(uint64(info.Size()))
+
+	if config.Upload && config.Uploader != nil {
+		r.uploadQueue = make(chan string, 16)
+		r.startUploader()
+	}
+
+	return r, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (n int, err error) {
+	r.mu.RLock()
+	n, err = r.file.Write(p)
+	r.mu.RUnlock()
+	if err != nil {
+		return n, err
+	}
+
+	if !r.config.Rotate {
+		return n, nil
+	}
+
+	bytesWritten := r.bytesWritten.Add(uint64(n))
+	var linesWritten uint64
+	if r.config.RotateLines > 0 {
+		linesWritten = r.linesWritten.Add(uint64(bytes.Count(p, []byte("\n"))))
+	}
+
+	if r.dueForRotation(bytesWritten, linesWritten) {
+		if e := r.rotate(); e != nil {
+			r.reportError(e)
+		}
+	}
+
+	return n, nil
+}
+
+func (r *rotatingFile) dueForRotation(bytesWritten, linesWritten uint64) bool {
+	if r.config.RotateFileSize > 0 && bytesWritten >= r.config.RotateFileSize {
+		return true
+	}
+
+	if r.config.RotateLines > 0 && linesWritten >= r.config.RotateLines {
+		return true
+	}
+
+	if r.config.RotateDaily {
+		r.mu.RLock()
+		day := r.day
+		r.mu.RUnlock()
+		if day != time.Now().Format("20060102") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rotate closes the current file, archives it alongside the base path, and
+// reopens the base path for further writes. It takes the full lock so
+// concurrent Write calls queue behind it, but rotation only happens once
+// every RotateFileSize/RotateLines/day, so the hot path stays uncontended.
+func (r *rotatingFile) rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Another writer may have already rotated while we were waiting on the
+	// lock, so re-check the thresholds before doing any work.
+	if !r.dueForRotationLocked() {
+		return nil
+	}
+
+	info, e := r.file.Stat()
+	if e != nil {
+		return e
+	}
+
+	if r.config.BeforeRotate != nil {
+		if e := r.config.BeforeRotate(r.path, info); e != nil {
+			return e
+		}
+	}
+
+	archivedPath := r.archivePath()
+
+	if e := r.file.Close(); e != nil {
+		return e
+	}
+
+	if e := os.Rename(r.path, archivedPath); e != nil {
+		return e
+	}
+
+	newFile, e := os.OpenFile(r.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, r.perm)
+	if e != nil {
+		return e
+	}
+	r.file = newFile
+	r.day = time.Now().Format("20060102")
+	r.bytesWritten.Store(0)
+	r.linesWritten.Store(0)
+
+	if r.uploadQueue != nil {
+		select {
+		case r.uploadQueue <- archivedPath:
+		default:
+			r.reportError(fmt.Errorf("cblog: upload queue full, dropping %s", archivedPath))
+		}
+	}
+
+	r.pruneArchives()
+
+	if r.config.AfterRotate != nil {
+		newInfo, e := newFile.Stat()
+		if e != nil {
+			return e
+		}
+		return r.config.AfterRotate(archivedPath, r.path, newInfo)
+	}
+
+	return nil
+}
+
+func (r *rotatingFile) dueForRotationLocked() bool {
+	if r.config.RotateFileSize > 0 && r.bytesWritten.Load() >= r.config.RotateFileSize {
+		return true
+	}
+
+	if r.config.RotateLines > 0 && r.linesWritten.Load() >= r.config.RotateLines {
+		return true
+	}
+
+	if r.config.RotateDaily && r.day != time.Now().Format("20060102") {
+		return true
+	}
+
+	return false
+}
+
+func (r *rotatingFile) archivePath() string {
+	candidate := r.path + "." + time.Now().Format("20060102-150405")
+	if _, e := os.Stat(candidate); os.IsNotExist(e) {
+		return candidate
+	}
+
+	for n := 1; ; n++ {
+		numbered := fmt.Sprintf("%s.%d", candidate, n)
+		if _, e := os.Stat(numbered); os.IsNotExist(e) {
+			return numbered
+		}
+	}
+}
+
+// pruneArchives deletes archives beyond RotateKeepCount and anything older
+// than MaxDays. Called while holding r.mu from rotate().
+func (r *rotatingFile) pruneArchives() {
+	if r.config.RotateKeepCount <= 0 && r.config.MaxDays <= 0 {
+		return
+	}
+
+	dir := filepath.Dir(r.path)
+	base := filepath.Base(r.path)
+
+	entries, e := os.ReadDir(dir)
+	if e != nil {
+		r.reportError(e)
+		return
+	}
+
+	type archive struct {
+		path    string
+		modTime time.Time
+	}
+	var archives []archive
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		suffix, ok := strings.CutPrefix(entry.Name(), base+".")
+		if !ok || !archiveSuffixPattern.MatchString(suffix) {
+			continue
+		}
+
+		info, e := entry.Info()
+		if e != nil {
+			continue
+		}
+		archives = append(archives, archive{
+			path:    filepath.Join(dir, entry.Name()),
+			modTime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].modTime.After(archives[j].modTime)
+	})
+
+	cutoff := time.Time{}
+	if r.config.MaxDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -r.config.MaxDays)
+	}
+
+	for i, a := range archives {
+		expiredByAge := r.config.MaxDays > 0 && a.modTime.Before(cutoff)
+		expiredByCount := r.config.RotateKeepCount > 0 && i >= r.config.RotateKeepCount
+
+		if expiredByAge || expiredByCount {
+			if e := os.Remove(a.path); e != nil {
+				r.reportError(e)
+			}
+		}
+	}
+}
+
+func (r *rotatingFile) startUploader() {
+	go func() {
+		if r.config.UploadInterval <= 0 {
+			for archivedPath := range r.uploadQueue {
+				r.upload(archivedPath)
+			}
+			return
+		}
+
+		ticker := time.NewTicker(r.config.UploadInterval)
+		defer ticker.Stop()
+
+		var pending []string
+		for {
+			select {
+			case archivedPath, ok := <-r.uploadQueue:
+				if !ok {
+					for _, p := range pending {
+						r.upload(p)
+					}
+					return
+				}
+				pending = append(pending, archivedPath)
+			case <-ticker.C:
+				for _, p := range pending {
+					r.upload(p)
+				}
+				pending = nil
+			}
+		}
+	}()
+}
+
+func (r *rotatingFile) upload(archivedPath string) {
+	content, e := os.ReadFile(archivedPath)
+	if e != nil {
+		r.reportError(e)
+		return
+	}
+
+	if e := r.config.Uploader(filepath.Base(archivedPath), content); e != nil {
+		r.reportError(e)
+	}
+}
+
+func (r *rotatingFile) reportError(e error) {
+	if r.config.ErrorReporter != nil {
+		r.config.ErrorReporter(e)
+	}
+}
+
+func (r *rotatingFile) Close() error {
+	if r.uploadQueue != nil {
+		close(r.uploadQueue)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,159 @@
+package cblog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestRotatingFile(t *testing.T, config LoggerConfig) (*rotatingFile, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	config.FilePath = path
+	config.FilePerm = 0o644
+
+	r, e := newRotatingFile(config)
+	if e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+
+	return r, dir
+}
+
+func countArchives(t *testing.T, dir string) int {
+	t.Helper()
+
+	entries, e := os.ReadDir(dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	n := 0
+	for _, entry := range entries {
+		if entry.Name() != "test.log" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRotatingFileRotatesOnSize(t *testing.T) {
+	r, dir := newTestRotatingFile(t, LoggerConfig{
+		Rotate:         true,
+		RotateFileSize: 10,
+	})
+
+	if _, e := r.Write([]byte("0123456789")); e != nil {
+		t.Fatal(e)
+	}
+
+	if countArchives(t, dir) != 1 {
+		t.Fatalf("expected one archive after hitting RotateFileSize, got %d", countArchives(t, dir))
+	}
+
+	if info, e := os.Stat(r.path); e != nil || info.Size() != 0 {
+		t.Fatalf("expected base file to be reset after rotation, stat err=%v", e)
+	}
+}
+
+func TestRotatingFileRotatesOnLines(t *testing.T) {
+	r, dir := newTestRotatingFile(t, LoggerConfig{
+		Rotate:      true,
+		RotateLines: 2,
+	})
+
+	if _, e := r.Write([]byte("one\ntwo\n")); e != nil {
+		t.Fatal(e)
+	}
+
+	if countArchives(t, dir) != 1 {
+		t.Fatalf("expected one archive after hitting RotateLines, got %d", countArchives(t, dir))
+	}
+}
+
+func TestRotatingFileNoRotateWithoutConfig(t *testing.T) {
+	r, dir := newTestRotatingFile(t, LoggerConfig{
+		RotateFileSize: 1,
+	})
+
+	if _, e := r.Write([]byte("0123456789")); e != nil {
+		t.Fatal(e)
+	}
+
+	if n := countArchives(t, dir); n != 0 {
+		t.Fatalf("expected no rotation when Rotate is false, got %d archives", n)
+	}
+}
+
+func TestPruneArchivesKeepCount(t *testing.T) {
+	r, dir := newTestRotatingFile(t, LoggerConfig{
+		Rotate:          true,
+		RotateFileSize:  1,
+		RotateKeepCount: 2,
+	})
+
+	for i := 0; i < 4; i++ {
+		if _, e := r.Write([]byte("x")); e != nil {
+			t.Fatal(e)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := countArchives(t, dir); n != 2 {
+		t.Fatalf("expected RotateKeepCount to leave 2 archives, got %d", n)
+	}
+}
+
+func TestPruneArchivesIgnoresUnrelatedFiles(t *testing.T) {
+	r, dir := newTestRotatingFile(t, LoggerConfig{
+		Rotate:          true,
+		RotateFileSize:  1,
+		RotateKeepCount: 1,
+	})
+
+	unrelated := filepath.Join(dir, "test.log.backup")
+	if e := os.WriteFile(unrelated, []byte("keep me"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, e := r.Write([]byte("x")); e != nil {
+			t.Fatal(e)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, e := os.Stat(unrelated); e != nil {
+		t.Fatalf("pruneArchives removed a file it didn't create: %v", e)
+	}
+}
+
+func TestPruneArchivesMaxDays(t *testing.T) {
+	r, dir := newTestRotatingFile(t, LoggerConfig{
+		Rotate:         true,
+		RotateFileSize: 1,
+		MaxDays:        1,
+	})
+
+	staleArchive := filepath.Join(dir, "test.log.20000101-000000")
+	if e := os.WriteFile(staleArchive, []byte("old"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+	old := time.Now().AddDate(0, 0, -30)
+	if e := os.Chtimes(staleArchive, old, old); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := r.Write([]byte("x")); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := os.Stat(staleArchive); !os.IsNotExist(e) {
+		t.Fatalf("expected archive older than MaxDays to be pruned, stat err=%v", e)
+	}
+}